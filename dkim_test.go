@@ -0,0 +1,101 @@
+package exchangesmtp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestDKIMSigner_SignsAndVerifies(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	signer := NewDKIMSigner("selector1", "example.com", key, []string{"From", "To", "Subject"})
+
+	mail := Mail{
+		MT:      PlainText,
+		From:    "sender@example.com",
+		To:      []string{"recipient@example.com"},
+		Subject: "Test DKIM",
+		Body:    "Signed body.",
+		Signers: []Signer{signer},
+	}
+
+	msg, err := mail.ToBytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.HasPrefix(msg, []byte("DKIM-Signature: ")) {
+		t.Fatalf("expected DKIM-Signature as the first header, got: %s", msg)
+	}
+
+	headers, body, err := splitMessage(msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hdr, err := parseHeaders(headers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sigValue := hdr.Get("DKIM-Signature")
+	bPos := strings.LastIndex(sigValue, "b=")
+	if bPos < 0 {
+		t.Fatalf("expected a b= tag in the signature, got: %s", sigValue)
+	}
+	tags := sigValue[:bPos+2]
+	sigB64 := strings.TrimRight(sigValue[bPos+2:], "; ")
+
+	sig, err := base64.StdEncoding.DecodeString(sigB64)
+	if err != nil {
+		t.Fatalf("unexpected error decoding signature: %v", err)
+	}
+
+	canon := bytes.NewBuffer(nil)
+	for _, name := range []string{"From", "To", "Subject"} {
+		canon.WriteString(relaxedHeader(name, hdr.Get(name)))
+		canon.WriteString("\r\n")
+	}
+	canon.WriteString(relaxedHeader("DKIM-Signature", tags))
+
+	digest := sha256.Sum256(canon.Bytes())
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature did not verify: %v", err)
+	}
+
+	wantBH := sha256.Sum256(canonicalizeBodyRelaxed(body))
+	if !strings.Contains(tags, "bh="+base64.StdEncoding.EncodeToString(wantBH[:])) {
+		t.Errorf("bh= tag does not match the canonicalized body hash, got: %s", tags)
+	}
+}
+
+func TestDKIMSigner_ErrorsOnMissingHeader(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	signer := NewDKIMSigner("selector1", "example.com", key, []string{"From", "To", "Date"})
+
+	mail := Mail{
+		MT:      PlainText,
+		From:    "sender@example.com",
+		To:      []string{"recipient@example.com"},
+		Subject: "Test DKIM",
+		Body:    "Signed body.",
+		Signers: []Signer{signer},
+	}
+
+	if _, err := mail.ToBytes(); err == nil {
+		t.Fatal("expected an error signing a header list containing a header ToBytes never emits, got nil")
+	}
+}
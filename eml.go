@@ -0,0 +1,249 @@
+package exchangesmtp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"strings"
+)
+
+// EMLToMail parses an RFC 5322 EML byte stream into a *Mail, the mirror
+// image of Mail.ToBytes. It walks multipart/mixed, multipart/related and
+// multipart/alternative parts, decoding quoted-printable and base64
+// transfer encodings along the way, and routes the result into Body,
+// Inline and Attachment depending on each part's Content-Disposition.
+// To, Cc, Bcc and Reply-To headers are parsed into their matching Mail
+// fields, and a multipart/alternative pair of text/plain and text/html
+// parts is parsed into TextBody and HTMLBody respectively.
+func EMLToMail(r io.Reader) (*Mail, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("exchangesmtp: parse eml: %s", err)
+	}
+
+	m := &Mail{}
+
+	if from := msg.Header.Get("From"); from != "" {
+		m.From = decodeAddress(from)
+	}
+	if to := msg.Header.Get("To"); to != "" {
+		for _, addr := range strings.Split(to, ",") {
+			m.To = append(m.To, decodeAddress(strings.TrimSpace(addr)))
+		}
+	}
+	if cc := msg.Header.Get("Cc"); cc != "" {
+		for _, addr := range strings.Split(cc, ",") {
+			m.Cc = append(m.Cc, decodeAddress(strings.TrimSpace(addr)))
+		}
+	}
+	if bcc := msg.Header.Get("Bcc"); bcc != "" {
+		for _, addr := range strings.Split(bcc, ",") {
+			m.Bcc = append(m.Bcc, decodeAddress(strings.TrimSpace(addr)))
+		}
+	}
+	if replyTo := msg.Header.Get("Reply-To"); replyTo != "" {
+		m.ReplyTo = decodeAddress(replyTo)
+	}
+	if subject := msg.Header.Get("Subject"); subject != "" {
+		m.Subject = decodeHeader(subject)
+	}
+
+	contentType := msg.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("exchangesmtp: parse eml: %s", err)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if err := m.readMultipart(msg.Body, params["boundary"]); err != nil {
+			return nil, fmt.Errorf("exchangesmtp: parse eml: %s", err)
+		}
+		return m, nil
+	}
+
+	body, err := decodeTransfer(msg.Body, msg.Header.Get("Content-Transfer-Encoding"))
+	if err != nil {
+		return nil, fmt.Errorf("exchangesmtp: parse eml: %s", err)
+	}
+	m.MT = mailTypeFromContentType(mediaType)
+	m.Body = string(body)
+
+	return m, nil
+}
+
+// ToEML renders m as an RFC 5322 message, the same bytes ToBytes
+// produces, as a string suitable for saving to a .eml file.
+func (m *Mail) ToEML() (string, error) {
+	b, err := m.ToBytes()
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// EMLToMailFromString parses an EML message held in a string.
+func EMLToMailFromString(s string) (*Mail, error) {
+	return EMLToMail(strings.NewReader(s))
+}
+
+// EMLToMailFromFile reads and parses an EML message stored on disk.
+func EMLToMailFromFile(path string) (*Mail, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("exchangesmtp: read eml file: %s", err)
+	}
+	defer f.Close()
+
+	return EMLToMail(f)
+}
+
+// readMultipart walks a multipart body, recursing into nested
+// multipart/related and multipart/alternative sections and routing leaf
+// parts into m.Body, m.Inline or m.Attachment.
+func (m *Mail) readMultipart(r io.Reader, boundary string) error {
+	mr := multipart.NewReader(r, boundary)
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := m.readPart(part); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readPart handles a single MIME part, recursing if it is itself
+// multipart and otherwise classifying it as body, inline or attachment.
+func (m *Mail) readPart(part *multipart.Part) error {
+	contentType := part.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return m.readMultipart(part, params["boundary"])
+	}
+
+	disposition, dispParams, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+	cid := strings.Trim(part.Header.Get("Content-Id"), "<>")
+
+	body, err := decodeTransfer(part, part.Header.Get("Content-Transfer-Encoding"))
+	if err != nil {
+		return err
+	}
+
+	name := dispParams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+
+	switch {
+	case disposition == "attachment":
+		m.Attachment = append(m.Attachment, AttachmentFile{
+			Name:        name,
+			ContentType: mediaType,
+			Body:        body,
+		})
+	case cid != "" && disposition == "inline":
+		m.Inline = append(m.Inline, InlineFile{
+			CID:         cid,
+			Name:        name,
+			ContentType: mediaType,
+			Body:        body,
+		})
+	case mediaType == "text/plain":
+		if m.Body == "" {
+			m.MT = PlainText
+			m.Body = string(body)
+		}
+		if m.TextBody == "" {
+			m.TextBody = string(body)
+		}
+	case mediaType == "text/html":
+		if m.Body == "" {
+			m.MT = HTML
+			m.Body = string(body)
+		}
+		if m.HTMLBody == "" {
+			m.HTMLBody = string(body)
+		}
+	default:
+		m.Attachment = append(m.Attachment, AttachmentFile{
+			Name:        name,
+			ContentType: mediaType,
+			Body:        body,
+		})
+	}
+
+	return nil
+}
+
+// decodeTransfer reads r fully and decodes it according to the given
+// Content-Transfer-Encoding, defaulting to a verbatim copy.
+func decodeTransfer(r io.Reader, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return io.ReadAll(base64.NewDecoder(base64.StdEncoding, r))
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(r))
+	default:
+		return io.ReadAll(r)
+	}
+}
+
+// decodeAddress normalizes addr (which may carry a "Name" <addr> display
+// name and/or an RFC 2047 encoded-word name) into the form FormatAddress
+// produces, so a display name survives a parse/re-render round trip.
+func decodeAddress(addr string) string {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return addr
+	}
+
+	return FormatAddress(parsed.Address, parsed.Name)
+}
+
+// decodeHeader Q/B-decodes a raw header value, falling back to the raw
+// value if it carries no encoded-word.
+func decodeHeader(value string) string {
+	dec := new(mime.WordDecoder)
+	decoded, err := dec.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+
+	return decoded
+}
+
+// mailTypeFromContentType maps a parsed media type to a MailType,
+// defaulting to PlainText for anything that isn't text/html.
+func mailTypeFromContentType(mediaType string) MailType {
+	if mediaType == "text/html" {
+		return HTML
+	}
+
+	return PlainText
+}
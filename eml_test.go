@@ -0,0 +1,167 @@
+package exchangesmtp
+
+import (
+	"testing"
+)
+
+func TestEMLToMail_PlainTextRoundTrip(t *testing.T) {
+	mail := Mail{
+		MT:      PlainText,
+		From:    "sender@example.com",
+		To:      []string{"recipient@example.com"},
+		Subject: "Round Trip",
+		Body:    "Hello from the round trip test.",
+	}
+
+	eml, err := mail.ToEML()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := EMLToMailFromString(eml)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if parsed.From != mail.From {
+		t.Errorf("From = %q, want %q", parsed.From, mail.From)
+	}
+	if len(parsed.To) != 1 || parsed.To[0] != mail.To[0] {
+		t.Errorf("To = %v, want %v", parsed.To, mail.To)
+	}
+	if parsed.Subject != mail.Subject {
+		t.Errorf("Subject = %q, want %q", parsed.Subject, mail.Subject)
+	}
+	if parsed.Body != mail.Body {
+		t.Errorf("Body = %q, want %q", parsed.Body, mail.Body)
+	}
+}
+
+func TestEMLToMail_WithAttachmentAndInline(t *testing.T) {
+	mail := Mail{
+		MT:      HTML,
+		From:    "sender@example.com",
+		To:      []string{"recipient@example.com"},
+		Subject: "With Files",
+		Body:    `<html><body><img src="cid:logo" /></body></html>`,
+		Inline: []InlineFile{
+			{CID: "logo", Name: "logo.png", ContentType: "image/png", Body: []byte("fake-image-data")},
+		},
+		Attachment: []AttachmentFile{
+			{Name: "report.txt", ContentType: "text/plain", Body: []byte("the report")},
+		},
+	}
+
+	eml, err := mail.ToEML()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := EMLToMailFromString(eml)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if parsed.MT != HTML {
+		t.Errorf("MT = %v, want HTML", parsed.MT)
+	}
+	if len(parsed.Inline) != 1 || parsed.Inline[0].CID != "logo" {
+		t.Errorf("Inline = %+v, want one part with CID logo", parsed.Inline)
+	}
+	if string(parsed.Inline[0].Body) != "fake-image-data" {
+		t.Errorf("Inline body = %q, want %q", parsed.Inline[0].Body, "fake-image-data")
+	}
+	if len(parsed.Attachment) != 1 || parsed.Attachment[0].Name != "report.txt" {
+		t.Errorf("Attachment = %+v, want one part named report.txt", parsed.Attachment)
+	}
+	if string(parsed.Attachment[0].Body) != "the report" {
+		t.Errorf("Attachment body = %q, want %q", parsed.Attachment[0].Body, "the report")
+	}
+}
+
+func TestEMLToMail_DisplayNameRoundTrip(t *testing.T) {
+	mail := Mail{
+		MT:      PlainText,
+		From:    FormatAddress("sender@example.com", "Jordan Wright"),
+		To:      []string{"recipient@example.com"},
+		Subject: "With Display Name",
+		Body:    "Hello.",
+	}
+
+	eml, err := mail.ToEML()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := EMLToMailFromString(eml)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if parsed.From != mail.From {
+		t.Errorf("From = %q, want %q", parsed.From, mail.From)
+	}
+}
+
+func TestEMLToMail_CcAndReplyToRoundTrip(t *testing.T) {
+	mail := Mail{
+		MT:      PlainText,
+		From:    "sender@example.com",
+		To:      []string{"recipient@example.com"},
+		Cc:      []string{"cc@example.com"},
+		ReplyTo: "reply@example.com",
+		Subject: "With Cc and Reply-To",
+		Body:    "Hello.",
+	}
+
+	eml, err := mail.ToEML()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := EMLToMailFromString(eml)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(parsed.Cc) != 1 || parsed.Cc[0] != mail.Cc[0] {
+		t.Errorf("Cc = %v, want %v", parsed.Cc, mail.Cc)
+	}
+	if parsed.ReplyTo != mail.ReplyTo {
+		t.Errorf("ReplyTo = %q, want %q", parsed.ReplyTo, mail.ReplyTo)
+	}
+}
+
+func TestEMLToMail_TextAndHTMLAlternativeRoundTrip(t *testing.T) {
+	mail := Mail{
+		From:     "sender@example.com",
+		To:       []string{"recipient@example.com"},
+		Subject:  "Alternative Body",
+		TextBody: "Plain version.",
+		HTMLBody: "<p>HTML version.</p>",
+	}
+
+	eml, err := mail.ToEML()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := EMLToMailFromString(eml)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if parsed.TextBody != mail.TextBody {
+		t.Errorf("TextBody = %q, want %q", parsed.TextBody, mail.TextBody)
+	}
+	if parsed.HTMLBody != mail.HTMLBody {
+		t.Errorf("HTMLBody = %q, want %q", parsed.HTMLBody, mail.HTMLBody)
+	}
+}
+
+func TestEMLToMailFromString_InvalidMessage(t *testing.T) {
+	_, err := EMLToMailFromString("not a valid email message")
+	if err == nil {
+		t.Error("expected an error for a malformed message, got nil")
+	}
+}
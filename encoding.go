@@ -0,0 +1,133 @@
+package exchangesmtp
+
+import (
+	"encoding/base64"
+	"io"
+	"mime/quotedprintable"
+	"sync"
+)
+
+// Encoding identifies a Content-Transfer-Encoding a part is sent with.
+type Encoding string
+
+const (
+	QuotedPrintable Encoding = "quoted-printable"
+	Base64          Encoding = "base64"
+	EightBit        Encoding = "8bit"
+	SevenBit        Encoding = "7bit"
+)
+
+// MaxLineLength is the maximum number of characters per encoded line,
+// per RFC 2045.
+const MaxLineLength = 76
+
+// encodersMu guards encoders, since RegisterEncoder may run concurrently
+// with sends that read it via encoderFor.
+var encodersMu sync.RWMutex
+
+// encoders maps a Content-Transfer-Encoding name to the writer factory
+// that implements it. RegisterEncoder extends this registry.
+var encoders = map[string]func(io.Writer) io.WriteCloser{
+	string(Base64):          newBase64Writer,
+	string(QuotedPrintable): func(w io.Writer) io.WriteCloser { return quotedprintable.NewWriter(w) },
+	string(EightBit):        func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} },
+	string(SevenBit):        func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} },
+}
+
+// RegisterEncoder installs (or replaces) the writer factory used for a
+// Content-Transfer-Encoding name, letting callers plug in custom
+// transfer encodings (e.g. for binary-safe pipelines).
+func RegisterEncoder(name string, factory func(io.Writer) io.WriteCloser) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	encoders[name] = factory
+}
+
+// encoderFor resolves the writer factory registered for enc, falling
+// back to quoted-printable for an unregistered encoding.
+func encoderFor(enc Encoding, w io.Writer) io.WriteCloser {
+	encodersMu.RLock()
+	factory, ok := encoders[string(enc)]
+	encodersMu.RUnlock()
+
+	if ok {
+		return factory(w)
+	}
+
+	return quotedprintable.NewWriter(w)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newBase64Writer streams base64 output wrapped at MaxLineLength columns.
+func newBase64Writer(w io.Writer) io.WriteCloser {
+	lb := &lineBreaker{w: w, max: MaxLineLength}
+	return &base64Writer{enc: base64.NewEncoder(base64.StdEncoding, lb), lb: lb}
+}
+
+type base64Writer struct {
+	enc io.WriteCloser
+	lb  *lineBreaker
+}
+
+func (b *base64Writer) Write(p []byte) (int, error) {
+	return b.enc.Write(p)
+}
+
+func (b *base64Writer) Close() error {
+	if err := b.enc.Close(); err != nil {
+		return err
+	}
+
+	return b.lb.Close()
+}
+
+// lineBreaker inserts a CRLF every max bytes written, regardless of how
+// the writes are chunked by the encoder sitting in front of it.
+type lineBreaker struct {
+	w   io.Writer
+	col int
+	max int
+}
+
+func (l *lineBreaker) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		space := l.max - l.col
+		chunk := p
+		if len(chunk) > space {
+			chunk = chunk[:space]
+		}
+
+		written, err := l.w.Write(chunk)
+		n += written
+		if err != nil {
+			return n, err
+		}
+
+		l.col += written
+		p = p[written:]
+
+		if l.col == l.max && len(p) > 0 {
+			if _, err := l.w.Write([]byte("\r\n")); err != nil {
+				return n, err
+			}
+			l.col = 0
+		}
+	}
+
+	return n, nil
+}
+
+func (l *lineBreaker) Close() error {
+	if l.col > 0 {
+		_, err := l.w.Write([]byte("\r\n"))
+		return err
+	}
+
+	return nil
+}
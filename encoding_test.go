@@ -0,0 +1,110 @@
+package exchangesmtp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMail_ToBytes_EightBitEncoding(t *testing.T) {
+	mail := Mail{
+		MT:       PlainText,
+		From:     "sender@example.com",
+		To:       []string{"recipient@example.com"},
+		Subject:  "Test 8bit",
+		Body:     "Plain body, sent as-is.",
+		Encoding: EightBit,
+	}
+
+	msg, err := mail.ToBytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Contains(msg, []byte("Content-Transfer-Encoding: 8bit")) {
+		t.Errorf("expected 8bit transfer encoding, got: %s", msg)
+	}
+	if !bytes.Contains(msg, []byte("Plain body, sent as-is.")) {
+		t.Errorf("expected body to be left untouched, got: %s", msg)
+	}
+}
+
+func TestWriteBytes_WrapsBase64At76Columns(t *testing.T) {
+	mail := &Mail{}
+	payload := bytes.Repeat([]byte("A"), 200)
+
+	buf := bytes.NewBuffer(nil)
+	if err := mail.writeBytes(buf, Base64, bytes.NewReader(payload)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, line := range strings.Split(strings.Trim(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > MaxLineLength {
+			t.Errorf("line length %d exceeds MaxLineLength %d: %q", len(line), MaxLineLength, line)
+		}
+	}
+}
+
+func TestRegisterEncoder_CustomEncoding(t *testing.T) {
+	const upperEncoding Encoding = "x-upper"
+
+	RegisterEncoder(string(upperEncoding), func(w io.Writer) io.WriteCloser {
+		return nopWriteCloser{&upperWriter{w: w}}
+	})
+
+	mail := Mail{
+		MT:       PlainText,
+		From:     "sender@example.com",
+		To:       []string{"recipient@example.com"},
+		Subject:  "Test Custom Encoding",
+		Body:     "hello",
+		Encoding: upperEncoding,
+	}
+
+	msg, err := mail.ToBytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Contains(msg, []byte("HELLO")) {
+		t.Errorf("expected custom encoder to run, got: %s", msg)
+	}
+}
+
+func TestRegisterEncoder_ConcurrentWithSend(t *testing.T) {
+	mail := Mail{
+		MT:      PlainText,
+		From:    "sender@example.com",
+		To:      []string{"recipient@example.com"},
+		Subject: "Concurrent Encoding",
+		Body:    "hello",
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterEncoder("x-concurrent", func(w io.Writer) io.WriteCloser {
+				return nopWriteCloser{w}
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := mail.ToBytes(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type upperWriter struct {
+	w io.Writer
+}
+
+func (u *upperWriter) Write(p []byte) (int, error) {
+	return u.w.Write(bytes.ToUpper(p))
+}
@@ -3,14 +3,14 @@ package exchangesmtp
 import (
 	"bytes"
 	"crypto/rand"
-	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"mime"
-	"mime/quotedprintable"
+	"net/mail"
 	"os"
-	"regexp"
 	"strings"
+	"unicode"
 )
 
 type MailType int
@@ -28,11 +28,35 @@ func (mt MailType) String() string {
 
 const charset = "UTF-8"
 
-var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-
-// ValidateEmail email-address is valid
+// ValidateEmail reports whether email is a valid address, either bare
+// ("user@example.com") or in "Name <user@example.com>" form.
 func ValidateEmail(email string) bool {
-	return emailRegex.MatchString(email)
+	_, err := mail.ParseAddress(email)
+	return err == nil
+}
+
+// FormatAddress builds an RFC 5322 address from addr and an optional
+// display name, Q-encoding the name when it contains non-ASCII characters.
+func FormatAddress(addr, name string) string {
+	if name == "" {
+		return addr
+	}
+
+	if isASCII(name) {
+		return fmt.Sprintf("%q <%s>", name, addr)
+	}
+
+	return fmt.Sprintf("%s <%s>", mime.QEncoding.Encode(charset, name), addr)
+}
+
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+
+	return true
 }
 
 // Mail is a struct for two types of email: plain text and html like.
@@ -41,17 +65,92 @@ type Mail struct {
 
 	From    string
 	To      []string
+	Cc      []string
+	Bcc     []string
+	ReplyTo string
 	Subject string
 	Body    string
 
+	// TextBody and HTMLBody, when both set, are emitted as a
+	// multipart/alternative pair instead of the single Body/MT part.
+	TextBody string
+	HTMLBody string
+
+	// Encoding selects the Content-Transfer-Encoding for the body part(s).
+	// Defaults to QuotedPrintable.
+	Encoding Encoding
+
+	// Signers run over the rendered headers and body in order, each
+	// contributing one header line (e.g. DKIM-Signature) prepended to
+	// the message.
+	Signers []Signer
+
 	Attachment []AttachmentFile
 	Inline     []InlineFile // для inline-картинок
 }
 
+// bodies resolves the effective text and HTML bodies, preferring
+// TextBody/HTMLBody and falling back to the legacy Body/MT fields when
+// neither is set.
+func (m *Mail) bodies() (text, html string) {
+	if m.TextBody != "" || m.HTMLBody != "" {
+		return m.TextBody, m.HTMLBody
+	}
+
+	if m.MT == HTML {
+		return "", m.Body
+	}
+
+	return m.Body, ""
+}
+
+// Recipients returns every address the message should reach — To, Cc and
+// Bcc combined — for use as the SMTP envelope. Bcc addresses are
+// deliberately left out of the rendered headers in ToBytes. Each address
+// is reduced to its bare mailbox (display names stripped), since that's
+// what SMTP envelope commands expect.
+func (m *Mail) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	for _, addr := range m.To {
+		recipients = append(recipients, bareAddress(addr))
+	}
+	for _, addr := range m.Cc {
+		recipients = append(recipients, bareAddress(addr))
+	}
+	for _, addr := range m.Bcc {
+		recipients = append(recipients, bareAddress(addr))
+	}
+
+	return recipients
+}
+
+// bareAddress strips any display name from addr (e.g. `"Jordan Wright"
+// <jw@example.com>` becomes `jw@example.com`), for use where only the
+// mailbox itself is valid, such as SMTP envelope commands. addr is
+// returned unchanged if it doesn't parse as an RFC 5322 address.
+func bareAddress(addr string) string {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return addr
+	}
+
+	return parsed.Address
+}
+
 type AttachmentFile struct {
 	Name        string
 	ContentType string
 	Body        []byte
+
+	// Reader and Path are alternatives to Body for large attachments:
+	// both are streamed straight through the transfer encoder rather
+	// than buffered in memory. Checked in order Body, Reader, Path.
+	Reader io.Reader
+	Path   string
+
+	// Encoding selects the Content-Transfer-Encoding for this attachment.
+	// Defaults to Base64.
+	Encoding Encoding
 }
 
 type InlineFile struct {
@@ -59,6 +158,10 @@ type InlineFile struct {
 	Name        string
 	ContentType string // ex: "image/png"
 	Body        []byte
+
+	// Encoding selects the Content-Transfer-Encoding for this part.
+	// Defaults to Base64.
+	Encoding Encoding
 }
 
 // generateBoundary creates a random MIME boundary
@@ -68,155 +171,290 @@ func generateBoundary() string {
 	return fmt.Sprintf("boundary-%x", buf[:])
 }
 
+// ToBytes renders the message into memory. It is a thin wrapper around
+// WriteTo for callers that want the whole message as a single []byte.
 func (m *Mail) ToBytes() ([]byte, error) {
-	msg := bytes.NewBuffer(nil)
+	buf := bytes.NewBuffer(nil)
+	if _, err := m.WriteTo(buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WriteTo renders the message directly to w, implementing io.WriterTo.
+// Attachments backed by Path or Reader are streamed straight through the
+// transfer encoder instead of being buffered in memory first, so large
+// files don't need to fit in RAM twice. When m.Signers is set, the whole
+// rendered message is buffered in memory before signing.
+func (m *Mail) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+
+	var err error
+	if len(m.Signers) > 0 {
+		err = m.writeSigned(cw)
+	} else {
+		err = m.writeTo(cw)
+	}
+
+	return cw.n, err
+}
+
+// countingWriter forwards writes to w while tallying the total byte
+// count, so WriteTo can report it without buffering.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
 
+	return n, err
+}
+
+func (m *Mail) writeTo(w io.Writer) error {
 	if len(m.To) == 0 {
-		return nil, errors.New("recipient list is empty")
+		return errors.New("recipient list is empty")
 	}
 
-	if len(m.Body) == 0 {
-		return nil, errors.New("email body is empty")
+	textBody, htmlBody := m.bodies()
+	if textBody == "" && htmlBody == "" {
+		return errors.New("email body is empty")
 	}
 
 	// From valid
 	if !ValidateEmail(m.From) {
-		return nil, fmt.Errorf("invalid From email address: %s", m.From)
+		return fmt.Errorf("invalid From email address: %s", m.From)
 	}
 
 	// To valid
 	for _, addr := range m.To {
 		if !ValidateEmail(addr) {
-			return nil, fmt.Errorf("invalid To email address: %s", addr)
+			return fmt.Errorf("invalid To email address: %s", addr)
+		}
+	}
+
+	// Cc valid
+	for _, addr := range m.Cc {
+		if !ValidateEmail(addr) {
+			return fmt.Errorf("invalid Cc email address: %s", addr)
 		}
 	}
 
+	// Bcc valid
+	for _, addr := range m.Bcc {
+		if !ValidateEmail(addr) {
+			return fmt.Errorf("invalid Bcc email address: %s", addr)
+		}
+	}
+
+	ws := stringWriter{w: w}
+
 	// write headers
-	msg.WriteString(fmt.Sprintf("From: %s\r\n", m.From))
-	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(m.To, ", ")))
+	ws.writeString(fmt.Sprintf("From: %s\r\n", m.From))
+	ws.writeString(fmt.Sprintf("To: %s\r\n", strings.Join(m.To, ", ")))
+	if len(m.Cc) > 0 {
+		ws.writeString(fmt.Sprintf("Cc: %s\r\n", strings.Join(m.Cc, ", ")))
+	}
+	// Bcc is deliberately not written to the rendered message (RFC 5322);
+	// recipients still receive it via Recipients() in the SMTP envelope.
+	if m.ReplyTo != "" {
+		ws.writeString(fmt.Sprintf("Reply-To: %s\r\n", m.ReplyTo))
+	}
 	sbj := mime.QEncoding.Encode("utf-8", m.Subject)
-	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", sbj))
-	msg.WriteString("MIME-Version: 1.0\r\n")
+	ws.writeString(fmt.Sprintf("Subject: %s\r\n", sbj))
+	ws.writeString("MIME-Version: 1.0\r\n")
 
 	boundary := generateBoundary()
 	hasAttachments := len(m.Attachment) > 0
 	hasInline := len(m.Inline) > 0
 
 	if hasAttachments || hasInline {
-		msg.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n\r\n", boundary))
-		msg.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+		ws.writeString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n\r\n", boundary))
+		ws.writeString(fmt.Sprintf("--%s\r\n", boundary))
 	}
 
 	// if exists inline-files, we need multipart/related
 	if hasInline {
 		relatedBoundary := generateBoundary()
-		msg.WriteString(fmt.Sprintf("Content-Type: multipart/related; boundary=%s\r\n\r\n", relatedBoundary))
-		msg.WriteString(fmt.Sprintf("--%s\r\n", relatedBoundary))
-
-		// write body
-		msg.WriteString(fmt.Sprintf("Content-Type: %s; charset=%s\r\n", m.MT.String(), charset))
-		msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		ws.writeString(fmt.Sprintf("Content-Type: multipart/related; boundary=%s\r\n\r\n", relatedBoundary))
+		ws.writeString(fmt.Sprintf("--%s\r\n", relatedBoundary))
 
-		qp := quotedprintable.NewWriter(msg)
-		_, err := qp.Write([]byte(m.Body))
-		if err != nil {
-			return nil, err
-		}
-		err = qp.Close()
-		if err != nil {
-			return nil, err
+		if err := m.writeCore(w, textBody, htmlBody); err != nil {
+			return err
 		}
 
 		// add inline files
 		for _, file := range m.Inline {
-			msg.WriteString(fmt.Sprintf("\r\n--%s\r\n", relatedBoundary))
+			ws.writeString(fmt.Sprintf("\r\n--%s\r\n", relatedBoundary))
 
 			contentType := file.ContentType
 			if contentType == "" {
 				contentType = "application/octet-stream"
 			}
 
-			msg.WriteString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n", contentType, file.Name))
-			msg.WriteString("Content-Transfer-Encoding: base64\r\n")
-			msg.WriteString(fmt.Sprintf("Content-ID: <%s>\r\n", file.CID))
-			msg.WriteString(fmt.Sprintf("Content-Disposition: inline; filename=\"%s\"\r\n", file.Name))
+			enc := file.Encoding
+			if enc == "" {
+				enc = Base64
+			}
+
+			ws.writeString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n", contentType, file.Name))
+			ws.writeString(fmt.Sprintf("Content-Transfer-Encoding: %s\r\n", enc))
+			ws.writeString(fmt.Sprintf("Content-ID: <%s>\r\n", file.CID))
+			ws.writeString(fmt.Sprintf("Content-Disposition: inline; filename=\"%s\"\r\n", file.Name))
 
-			if err := m.writeBytes(msg, file.Body); err != nil {
-				return nil, err
+			if err := m.writeBytes(w, enc, bytes.NewReader(file.Body)); err != nil {
+				return err
 			}
 		}
-		msg.WriteString(fmt.Sprintf("\r\n--%s--\r\n", relatedBoundary))
+		ws.writeString(fmt.Sprintf("\r\n--%s--\r\n", relatedBoundary))
 	} else {
-		// write body (without inline)
-		msg.WriteString(fmt.Sprintf("Content-Type: %s; charset=%s\r\n", m.MT.String(), charset))
-		msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
-
-		qp := quotedprintable.NewWriter(msg)
-		_, err := qp.Write([]byte(m.Body))
-		if err != nil {
-			return nil, err
-		}
-		err = qp.Close()
-		if err != nil {
-			return nil, err
+		if err := m.writeCore(w, textBody, htmlBody); err != nil {
+			return err
 		}
 	}
 
 	// add attachments
 	if hasAttachments {
 		for _, file := range m.Attachment {
-			msg.WriteString(fmt.Sprintf("\r\n--%s\r\n", boundary))
+			ws.writeString(fmt.Sprintf("\r\n--%s\r\n", boundary))
 
 			contentType := file.ContentType
 			if contentType == "" {
 				contentType = "application/octet-stream"
 			}
-			msg.WriteString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n", contentType, file.Name))
-			msg.WriteString("Content-Transfer-Encoding: base64\r\n")
-			msg.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n", file.Name))
-
-			if len(file.Body) > 0 {
-				if err := m.writeBytes(msg, file.Body); err != nil {
-					return nil, err
-				}
-			} else {
-				if err := m.writeFile(msg, file.Name); err != nil {
-					return nil, err
-				}
+			enc := file.Encoding
+			if enc == "" {
+				enc = Base64
+			}
+
+			ws.writeString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n", contentType, file.Name))
+			ws.writeString(fmt.Sprintf("Content-Transfer-Encoding: %s\r\n", enc))
+			ws.writeString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n", file.Name))
+
+			if err := m.writeAttachment(w, enc, file); err != nil {
+				return err
 			}
 		}
 	}
 
 	if hasAttachments || hasInline {
-		msg.WriteString(fmt.Sprintf("\r\n--%s--\r\n", boundary))
+		ws.writeString(fmt.Sprintf("\r\n--%s--\r\n", boundary))
 	}
 
-	return msg.Bytes(), nil
+	return ws.err
 }
 
-func (m *Mail) writeBytes(msg *bytes.Buffer, file []byte) error {
-	payload := make([]byte, base64.StdEncoding.EncodedLen(len(file)))
-	base64.StdEncoding.Encode(payload, file)
-	msg.WriteString("\r\n")
-	for index, line := 0, len(payload); index < line; index++ {
-		msg.WriteByte(payload[index])
-		if (index+1)%76 == 0 {
-			msg.WriteString("\r\n")
+// stringWriter writes successive strings to w, remembering the first
+// error so callers can check it once at the end instead of after every
+// call.
+type stringWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ws *stringWriter) writeString(s string) {
+	if ws.err != nil {
+		return
+	}
+	_, ws.err = io.WriteString(ws.w, s)
+}
+
+// writeCore writes the innermost content part: a single text/plain or
+// text/html part, or, when both textBody and htmlBody are set, a
+// multipart/alternative section containing one of each.
+func (m *Mail) writeCore(w io.Writer, textBody, htmlBody string) error {
+	switch {
+	case textBody != "" && htmlBody != "":
+		boundary := generateBoundary()
+		ws := stringWriter{w: w}
+		ws.writeString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=%s\r\n\r\n", boundary))
+		ws.writeString(fmt.Sprintf("--%s\r\n", boundary))
+		if ws.err != nil {
+			return ws.err
+		}
+		if err := m.writeBodyPart(w, PlainText, textBody); err != nil {
+			return err
 		}
+		ws.writeString(fmt.Sprintf("\r\n--%s\r\n", boundary))
+		if ws.err != nil {
+			return ws.err
+		}
+		if err := m.writeBodyPart(w, HTML, htmlBody); err != nil {
+			return err
+		}
+		ws.writeString(fmt.Sprintf("\r\n--%s--\r\n", boundary))
+		return ws.err
+	case htmlBody != "":
+		return m.writeBodyPart(w, HTML, htmlBody)
+	default:
+		return m.writeBodyPart(w, PlainText, textBody)
+	}
+}
+
+// writeBodyPart encodes a single text part using m.Encoding, defaulting
+// to quoted-printable.
+func (m *Mail) writeBodyPart(w io.Writer, mt MailType, body string) error {
+	enc := m.Encoding
+	if enc == "" {
+		enc = QuotedPrintable
+	}
+
+	ws := stringWriter{w: w}
+	ws.writeString(fmt.Sprintf("Content-Type: %s; charset=%s\r\n", mt.String(), charset))
+	ws.writeString(fmt.Sprintf("Content-Transfer-Encoding: %s\r\n\r\n", enc))
+	if ws.err != nil {
+		return ws.err
 	}
 
-	return nil
+	encW := encoderFor(enc, w)
+	if _, err := io.WriteString(encW, body); err != nil {
+		return err
+	}
+
+	return encW.Close()
 }
 
-func (m *Mail) writeFile(msg *bytes.Buffer, fileName string) error {
-	file, err := os.ReadFile(fileName)
-	if err != nil {
+// writeBytes streams r through the writer registered for enc. Base64 and
+// quoted-printable output is wrapped at MaxLineLength columns per RFC
+// 2045; 7bit and 8bit data is written unchanged, without wrapping.
+func (m *Mail) writeBytes(w io.Writer, enc Encoding, r io.Reader) error {
+	if _, err := io.WriteString(w, "\r\n"); err != nil {
+		return err
+	}
+
+	encW := encoderFor(enc, w)
+	if _, err := io.Copy(encW, r); err != nil {
 		return err
 	}
 
-	if err = m.writeBytes(msg, file); err != nil {
+	return encW.Close()
+}
+
+// writeAttachment streams an attachment's data through the transfer
+// encoder, preferring an in-memory Body, then an explicit Reader, then
+// Path, and finally falling back to treating Name as a path on disk.
+func (m *Mail) writeAttachment(w io.Writer, enc Encoding, file AttachmentFile) error {
+	switch {
+	case len(file.Body) > 0:
+		return m.writeBytes(w, enc, bytes.NewReader(file.Body))
+	case file.Reader != nil:
+		return m.writeBytes(w, enc, file.Reader)
+	case file.Path != "":
+		return m.writeAttachmentFile(w, enc, file.Path)
+	default:
+		return m.writeAttachmentFile(w, enc, file.Name)
+	}
+}
+
+func (m *Mail) writeAttachmentFile(w io.Writer, enc Encoding, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
 		return err
 	}
+	defer f.Close()
 
-	return nil
+	return m.writeBytes(w, enc, f)
 }
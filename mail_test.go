@@ -3,6 +3,8 @@ package exchangesmtp
 import (
 	"bytes"
 	"encoding/base64"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -211,3 +213,219 @@ func TestMail_ToBytes_MultipleRecipients(t *testing.T) {
 		t.Errorf("expected comma-separated recipients, got: %s", msg)
 	}
 }
+
+func TestMail_ToBytes_CcAndReplyTo(t *testing.T) {
+	mail := Mail{
+		MT:      PlainText,
+		From:    "sender@example.com",
+		To:      []string{"recipient@example.com"},
+		Cc:      []string{"cc1@example.com", "cc2@example.com"},
+		Bcc:     []string{"hidden@example.com"},
+		ReplyTo: "replies@example.com",
+		Subject: "Test Cc and Reply-To",
+		Body:    "Test body",
+	}
+
+	msg, err := mail.ToBytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Contains(msg, []byte("Cc: cc1@example.com, cc2@example.com")) {
+		t.Errorf("expected Cc header, got: %s", msg)
+	}
+	if !bytes.Contains(msg, []byte("Reply-To: replies@example.com")) {
+		t.Errorf("expected Reply-To header, got: %s", msg)
+	}
+	if bytes.Contains(msg, []byte("hidden@example.com")) {
+		t.Errorf("Bcc address must not appear in the rendered message, got: %s", msg)
+	}
+}
+
+func TestMail_Recipients(t *testing.T) {
+	mail := Mail{
+		To:  []string{"to@example.com"},
+		Cc:  []string{"cc@example.com"},
+		Bcc: []string{"bcc@example.com"},
+	}
+
+	got := mail.Recipients()
+	want := []string{"to@example.com", "cc@example.com", "bcc@example.com"}
+
+	if len(got) != len(want) {
+		t.Fatalf("Recipients() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Recipients()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMail_Recipients_StripsDisplayNames(t *testing.T) {
+	mail := Mail{
+		To:  []string{`"Jordan Wright" <to@example.com>`},
+		Cc:  []string{"Cc Person <cc@example.com>"},
+		Bcc: []string{"bcc@example.com"},
+	}
+
+	got := mail.Recipients()
+	want := []string{"to@example.com", "cc@example.com", "bcc@example.com"}
+
+	if len(got) != len(want) {
+		t.Fatalf("Recipients() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Recipients()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFormatAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		name string
+		want string
+	}{
+		{"jw@example.com", "", "jw@example.com"},
+		{"jw@example.com", "Jordan Wright", `"Jordan Wright" <jw@example.com>`},
+	}
+
+	for _, tt := range tests {
+		got := FormatAddress(tt.addr, tt.name)
+		if got != tt.want {
+			t.Errorf("FormatAddress(%q, %q) = %q, want %q", tt.addr, tt.name, got, tt.want)
+		}
+	}
+
+	encoded := FormatAddress("jw@example.com", "Жордан")
+	if !strings.Contains(encoded, "jw@example.com") || strings.Contains(encoded, "Жордан") {
+		t.Errorf("FormatAddress with non-ASCII name should Q-encode it, got: %q", encoded)
+	}
+}
+
+func TestValidateEmail_NameAddrForm(t *testing.T) {
+	if !ValidateEmail(`"Jordan Wright" <jw@example.com>`) {
+		t.Error("expected Name <addr> form to validate")
+	}
+	if ValidateEmail("not-an-email") {
+		t.Error("expected invalid address to fail validation")
+	}
+}
+
+func TestMail_ToBytes_TextAndHTMLAlternative(t *testing.T) {
+	mail := Mail{
+		From:     "sender@example.com",
+		To:       []string{"recipient@example.com"},
+		Subject:  "Test Alternative",
+		TextBody: "Plain text version.",
+		HTMLBody: "<p>HTML version.</p>",
+	}
+
+	msg, err := mail.ToBytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Contains(msg, []byte("Content-Type: multipart/alternative")) {
+		t.Errorf("expected multipart/alternative, got: %s", msg)
+	}
+	if !bytes.Contains(msg, []byte("Content-Type: text/plain")) {
+		t.Errorf("expected text/plain part, got: %s", msg)
+	}
+	if !bytes.Contains(msg, []byte("Content-Type: text/html")) {
+		t.Errorf("expected text/html part, got: %s", msg)
+	}
+}
+
+func TestMail_ToBytes_TextAndHTMLAlternativeWithInlineAndAttachment(t *testing.T) {
+	mail := Mail{
+		From:     "sender@example.com",
+		To:       []string{"recipient@example.com"},
+		Subject:  "Test Alternative Nested",
+		TextBody: "Plain text version.",
+		HTMLBody: `<p><img src="cid:logo"></p>`,
+		Inline: []InlineFile{
+			{CID: "logo", Name: "logo.png", ContentType: "image/png", Body: []byte("img")},
+		},
+		Attachment: []AttachmentFile{
+			{Name: "report.txt", Body: []byte("report")},
+		},
+	}
+
+	msg, err := mail.ToBytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Contains(msg, []byte("multipart/mixed")) {
+		t.Errorf("expected multipart/mixed, got: %s", msg)
+	}
+	if !bytes.Contains(msg, []byte("multipart/related")) {
+		t.Errorf("expected multipart/related, got: %s", msg)
+	}
+	if !bytes.Contains(msg, []byte("multipart/alternative")) {
+		t.Errorf("expected multipart/alternative, got: %s", msg)
+	}
+}
+
+func TestMail_WriteTo_MatchesToBytes(t *testing.T) {
+	mail := Mail{
+		MT:      PlainText,
+		From:    "sender@example.com",
+		To:      []string{"recipient@example.com"},
+		Subject: "Test WriteTo",
+		Body:    "Hello via WriteTo.",
+	}
+
+	want, err := mail.ToBytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	n, err := mail.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo reported %d bytes, buffer holds %d", n, buf.Len())
+	}
+	if buf.String() != string(want) {
+		t.Errorf("WriteTo output differs from ToBytes:\n%s\nvs\n%s", buf.String(), want)
+	}
+}
+
+func TestMail_ToBytes_AttachmentFromReaderAndPath(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/report.txt"
+	if err := os.WriteFile(path, []byte("from disk"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mail := Mail{
+		MT:      PlainText,
+		From:    "sender@example.com",
+		To:      []string{"recipient@example.com"},
+		Subject: "Test Streamed Attachments",
+		Body:    "See attached.",
+		Attachment: []AttachmentFile{
+			{Name: "from-reader.txt", Reader: strings.NewReader("from reader")},
+			{Name: "from-path.txt", Path: path},
+		},
+	}
+
+	msg, err := mail.ToBytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Contains(msg, []byte(base64.StdEncoding.EncodeToString([]byte("from reader")))) {
+		t.Errorf("expected Reader-backed attachment content, got: %s", msg)
+	}
+	if !bytes.Contains(msg, []byte(base64.StdEncoding.EncodeToString([]byte("from disk")))) {
+		t.Errorf("expected Path-backed attachment content, got: %s", msg)
+	}
+}
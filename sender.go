@@ -0,0 +1,83 @@
+package exchangesmtp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+)
+
+// MailSender delivers Mail messages over SMTP using the given
+// credentials and server address ("host:port").
+type MailSender struct {
+	user     string
+	password string
+	server   string
+}
+
+// NewMailSender creates a MailSender authenticating as user/password
+// against server ("host:port").
+func NewMailSender(user, password, server string) *MailSender {
+	return &MailSender{
+		user:     user,
+		password: password,
+		server:   server,
+	}
+}
+
+// Send renders m and delivers it over SMTP, streaming it straight to the
+// DATA writer via WriteTo so large attachments are not fully buffered in
+// memory (unless m.Signers is set, in which case WriteTo buffers the
+// whole message to sign it). The envelope recipients come from m.Recipients(),
+// so Bcc addresses are delivered even though they never appear in the
+// rendered headers.
+func (ms *MailSender) Send(m Mail) error {
+	host, _, err := net.SplitHostPort(ms.server)
+	if err != nil {
+		host = ms.server
+	}
+
+	client, err := smtp.Dial(ms.server)
+	if err != nil {
+		return fmt.Errorf("exchangesmtp: send mail: %s", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("exchangesmtp: send mail: %s", err)
+		}
+	}
+
+	if ok, _ := client.Extension("AUTH"); ok {
+		auth := smtp.PlainAuth("", ms.user, ms.password, host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("exchangesmtp: send mail: %s", err)
+		}
+	}
+
+	if err := client.Mail(bareAddress(m.From)); err != nil {
+		return fmt.Errorf("exchangesmtp: send mail: %s", err)
+	}
+	for _, addr := range m.Recipients() {
+		if err := client.Rcpt(addr); err != nil {
+			return fmt.Errorf("exchangesmtp: send mail: %s", err)
+		}
+	}
+
+	data, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("exchangesmtp: send mail: %s", err)
+	}
+
+	if _, err := m.WriteTo(data); err != nil {
+		data.Close()
+		return fmt.Errorf("exchangesmtp: send mail: %s", err)
+	}
+
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("exchangesmtp: send mail: %s", err)
+	}
+
+	return client.Quit()
+}